@@ -0,0 +1,16 @@
+// Package config re-exports kairos-agent's runtime configuration type.
+// Historically this package carried its own Config struct; it has since been
+// folded into kairos-sdk/types/config so that providers consuming the SDK see
+// the exact same shape kairos-agent itself decodes cloud-config into. The
+// alias is kept so existing imports of "pkg/config" across the agent don't
+// need to change.
+package config
+
+import (
+	sdkconfig "github.com/kairos-io/kairos-sdk/types/config"
+)
+
+// Config is kairos-agent's fully decoded runtime configuration, combining
+// the parsed cloud-config document with the filesystem/logger/runner
+// dependencies install actions operate against.
+type Config = sdkconfig.Config
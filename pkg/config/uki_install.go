@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/kairos-io/kairos-agent/v2/pkg/types/v1"
+)
+
+// ApplyUkiPartitions populates the partition-size-related fields of spec from
+// cfg's cloud-config. kairos-sdk's install.Install.Partitions has no concept
+// of an EFI/bootloader partition size (UKI installs are the only flavour
+// that needs one), so install.partitions.bootloader.size is read directly
+// out of the raw cloud-config document via cfg.Collector.Query rather than
+// through a typed struct field.
+func ApplyUkiPartitions(cfg *Config, spec *v1.InstallUkiSpec) error {
+	raw, err := cfg.Collector.Query("install.partitions.bootloader.size")
+	if err != nil {
+		return fmt.Errorf("querying install.partitions.bootloader.size: %w", err)
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	size, err := strconv.ParseUint(raw, 10, 0)
+	if err != nil {
+		return fmt.Errorf("parsing install.partitions.bootloader.size %q: %w", raw, err)
+	}
+	spec.EFISize = uint(size)
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	v1 "github.com/kairos-io/kairos-agent/v2/pkg/types/v1"
+	"github.com/kairos-io/kairos-sdk/collector"
+)
+
+func newTestConfig(values collector.ConfigValues) *Config {
+	return &Config{Collector: collector.Config{Values: values}}
+}
+
+func TestApplyUkiPartitionsSetsEFISize(t *testing.T) {
+	cfg := newTestConfig(collector.ConfigValues{
+		"install": map[string]interface{}{
+			"partitions": map[string]interface{}{
+				"bootloader": map[string]interface{}{
+					"size": 128,
+				},
+			},
+		},
+	})
+	spec := &v1.InstallUkiSpec{}
+
+	if err := ApplyUkiPartitions(cfg, spec); err != nil {
+		t.Fatalf("ApplyUkiPartitions() error = %v", err)
+	}
+	if spec.EFISize != 128 {
+		t.Errorf("EFISize = %d, want 128", spec.EFISize)
+	}
+}
+
+func TestApplyUkiPartitionsLeavesZeroWhenUnset(t *testing.T) {
+	cfg := newTestConfig(nil)
+	spec := &v1.InstallUkiSpec{}
+
+	if err := ApplyUkiPartitions(cfg, spec); err != nil {
+		t.Fatalf("ApplyUkiPartitions() error = %v", err)
+	}
+	if spec.EFISize != 0 {
+		t.Errorf("EFISize = %d, want 0", spec.EFISize)
+	}
+}
+
+func TestApplyUkiPartitionsRejectsNonNumericSize(t *testing.T) {
+	cfg := newTestConfig(collector.ConfigValues{
+		"install": map[string]interface{}{
+			"partitions": map[string]interface{}{
+				"bootloader": map[string]interface{}{
+					"size": "not-a-number",
+				},
+			},
+		},
+	})
+	spec := &v1.InstallUkiSpec{}
+
+	if err := ApplyUkiPartitions(cfg, spec); err == nil {
+		t.Fatal("ApplyUkiPartitions() expected error for non-numeric size")
+	}
+}
@@ -0,0 +1,132 @@
+package v1
+
+// InstallUkiSpec represents the installation options for a UKI (Unified
+// Kernel Image) flavoured deployment. Unlike the regular InstallSpec, there
+// is no image unpacking/selinux relabelling/grub install involved: the EFI
+// and OEM/persistent partitions are simply populated with the prebuilt UKI
+// artifacts carried by Active.Source.
+type InstallUkiSpec struct {
+	Target     string `yaml:"target,omitempty" mapstructure:"target"`
+	Partitions ElementalPartitions
+	Active     Image
+	// CloudInit entries are resolved by pkg/uki/cloudinit.Resolver and accept
+	// local paths, file://, http(s)://, tpm:// (a sealed blob on a TPM NV
+	// index, unsealed against PCR 7+11) and oci:// references.
+	CloudInit   []string `yaml:"cloud-init,omitempty" mapstructure:"cloud-init"`
+	SkipEntries []string `yaml:"uki-skip-entries,omitempty" mapstructure:"uki-skip-entries"`
+
+	// CmdlineMode controls how InstallAction handles EFI sources that carry
+	// several cmdline-variant artifacts (base + per-cmdline variants produced
+	// by enki's --extra-cmdline/--extend-cmdline). Defaults to CmdlineModeAll.
+	CmdlineMode CmdlineMode `yaml:"uki-cmdline-mode,omitempty" mapstructure:"uki-cmdline-mode"`
+	// CmdlineAllowList restricts which tags get installed when CmdlineMode is
+	// CmdlineModeAllowList.
+	CmdlineAllowList []string `yaml:"uki-cmdline-allow-list,omitempty" mapstructure:"uki-cmdline-allow-list"`
+	// DefaultCmdlineTag picks which installed tag becomes the "default" entry
+	// in loader.conf. If empty, the legacy "active" entry (or the first
+	// installed tag, in unique-cmdline mode) is used.
+	DefaultCmdlineTag string `yaml:"uki-default-cmdline-tag,omitempty" mapstructure:"uki-default-cmdline-tag"`
+	// SkipBootManager disables registering a UEFI boot manager entry for the
+	// installed artifact, for live/ISO scenarios that have no efivarfs to
+	// write to (e.g. booting under a hypervisor without OVMF NVRAM support).
+	SkipBootManager bool `yaml:"uki-skip-boot-manager,omitempty" mapstructure:"uki-skip-boot-manager"`
+	// EFISize overrides the size, in MiB, of the EFI/bootloader partition
+	// PartitionAndFormatDevice creates. If left zero, InstallAction falls
+	// back to the install.partitions.bootloader.size cloud-config key (see
+	// config.ApplyUkiPartitions), and finally to DefaultEFISizeMiB.
+	EFISize uint `yaml:"efi-size,omitempty" mapstructure:"efi-size"`
+}
+
+// DefaultEFISizeMiB is the size, in MiB, used for the EFI partition when
+// InstallUkiSpec.EFISize is left unset.
+const DefaultEFISizeMiB uint = 512
+
+// CmdlineMode selects how InstallAction reconciles multiple cmdline-variant
+// UKI artifacts found on the same EFI source.
+type CmdlineMode string
+
+const (
+	// CmdlineModeAll installs every cmdline variant found on the EFI source.
+	CmdlineModeAll CmdlineMode = "all"
+	// CmdlineModeAllowList installs only the tagged variants listed in
+	// InstallUkiSpec.CmdlineAllowList (the untagged/legacy "artifact" entry,
+	// if present, is always installed).
+	CmdlineModeAllowList CmdlineMode = "allow-list"
+	// CmdlineModeUniqueCmdline installs at most one variant per distinct
+	// cmdline value, assigning each survivor a role (active/passive/recovery)
+	// in the order they are found and skipping later duplicates. The fourth
+	// and any later distinct cmdline get the role "extra".
+	CmdlineModeUniqueCmdline CmdlineMode = "unique-cmdline"
+)
+
+// GetPartitions returns the partitions involved in the UKI install so they
+// can be partitioned, formatted and mounted by elemental.Elemental the same
+// way a regular InstallSpec would.
+func (i *InstallUkiSpec) GetPartitions() ElementalPartitions {
+	return i.Partitions
+}
+
+// ElementalPartitions groups the partitions that make up a UKI deployment.
+type ElementalPartitions struct {
+	EFI        *Partition
+	Persistent *Partition
+}
+
+// PartitionsByMountPoint returns the partitions in the set, ordered so that
+// nested mount points are mounted/unmounted in the right order. When
+// unmounting is true, the order is reversed.
+func (e ElementalPartitions) PartitionsByMountPoint(unmounting bool) []*Partition {
+	parts := []*Partition{}
+	if e.EFI != nil {
+		parts = append(parts, e.EFI)
+	}
+	if e.Persistent != nil {
+		parts = append(parts, e.Persistent)
+	}
+	if unmounting {
+		reversed := make([]*Partition, len(parts))
+		for i, p := range parts {
+			reversed[len(parts)-1-i] = p
+		}
+		return reversed
+	}
+	return parts
+}
+
+// Partition represents a single disk partition relevant to the install.
+type Partition struct {
+	Label      string
+	Size       uint
+	MountPoint string
+	Path       string
+	// Disk is the block device the partition lives on, e.g. "/dev/sda".
+	Disk string
+	// PartNum is the 1-based partition number of Path on Disk.
+	PartNum int
+}
+
+// Image describes the source of the UKI artifacts to dump onto the EFI
+// partition (a container image, a directory, a raw file, etc).
+type Image struct {
+	Source Source
+	Label  string
+}
+
+// Source identifies where an Image's contents come from.
+type Source struct {
+	URI    string
+	Digest string
+	Type   string
+	// SizeMiB is the on-disk size of the source contents, in MiB, when known
+	// ahead of time (e.g. resolved from an OCI image's uncompressed layer
+	// size). Zero means unknown, in which case EFI partition size validation
+	// is skipped.
+	SizeMiB uint
+	// Artifacts is the number of distinct UKI artifacts (cmdline variants)
+	// the source carries. Defaults to 1 when unset.
+	Artifacts int
+}
+
+func (s Source) String() string {
+	return s.URI
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/kairos-io/kairos-agent/v2/pkg/constants"
+	"github.com/twpayne/go-vfs/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// InstallStateSchemaVersion is bumped whenever the shape of InstallState
+// changes in a way that is not backwards compatible for readers.
+const InstallStateSchemaVersion = 1
+
+// InstallStateFile is the name of the state file written under both the EFI
+// and OEM/persistent partitions after a UKI install.
+const InstallStateFile = "state.yaml"
+
+// InstallState is the machine-readable record of what a UKI install actually
+// did, so that later upgrade/reset flows know which entries are currently
+// deployed and which one is active, without having to re-derive it by
+// re-parsing every .conf file on the EFI partition.
+type InstallState struct {
+	SchemaVersion int                 `yaml:"schemaVersion"`
+	Source        Source              `yaml:"source"`
+	Partitions    []InstallStatePart  `yaml:"partitions"`
+	Entries       []InstallStateEntry `yaml:"entries"`
+	Skipped       []InstallStateSkip  `yaml:"skipped,omitempty"`
+}
+
+// InstallStatePart records the label/size of a partition touched by install.
+type InstallStatePart struct {
+	Label string `yaml:"label"`
+	Size  uint   `yaml:"size"`
+}
+
+// InstallStateEntry records one installed UKI entry.
+type InstallStateEntry struct {
+	File    string `yaml:"file"`
+	Cmdline string `yaml:"cmdline"`
+	Role    string `yaml:"role"` // e.g. "active", "passive", "recovery"
+	Default bool   `yaml:"default"`
+}
+
+// InstallStateSkip records an entry that matched SkipEntries or was excluded
+// by CmdlineMode and was removed instead of installed, along with the reason
+// it was skipped.
+type InstallStateSkip struct {
+	Cmdline string `yaml:"cmdline"`
+	Reason  string `yaml:"reason"`
+}
+
+// WriteInstallState marshals state and writes it to path on fs.
+func WriteInstallState(fs vfs.FS, state *InstallState, path string) error {
+	state.SchemaVersion = InstallStateSchemaVersion
+	out, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshalling install state: %w", err)
+	}
+	return fs.WriteFile(path, out, constants.FilePerm)
+}
+
+// LoadInstallState reads and unmarshals the install state previously written
+// by WriteInstallState from path on fs.
+func LoadInstallState(fs vfs.FS, path string) (*InstallState, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading install state %s: %w", path, err)
+	}
+	state := &InstallState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshalling install state %s: %w", path, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/twpayne/go-vfs/v4/vfst"
+)
+
+func TestWriteLoadInstallStateRoundTrip(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(nil)
+	if err != nil {
+		t.Fatalf("creating test fs: %v", err)
+	}
+	defer cleanup()
+
+	want := &InstallState{
+		Source: Source{URI: "oci://example.com/kairos:latest"},
+		Partitions: []InstallStatePart{
+			{Label: "COS_EFI", Size: 64},
+		},
+		Entries: []InstallStateEntry{
+			{File: "kairos-active.conf", Cmdline: "root=LABEL=COS_ACTIVE", Role: "active", Default: true},
+			{File: "kairos-passive.conf", Cmdline: "root=LABEL=COS_PASSIVE", Role: "passive"},
+		},
+		Skipped: []InstallStateSkip{
+			{Cmdline: "root=LABEL=COS_OLD", Reason: "matched uki-skip-entries"},
+		},
+	}
+
+	path := "/state.yaml"
+	if err := WriteInstallState(fs, want, path); err != nil {
+		t.Fatalf("WriteInstallState() error = %v", err)
+	}
+
+	got, err := LoadInstallState(fs, path)
+	if err != nil {
+		t.Fatalf("LoadInstallState() error = %v", err)
+	}
+
+	if got.SchemaVersion != InstallStateSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", got.SchemaVersion, InstallStateSchemaVersion)
+	}
+	if got.Source != want.Source {
+		t.Errorf("Source = %+v, want %+v", got.Source, want.Source)
+	}
+	if len(got.Entries) != len(want.Entries) {
+		t.Fatalf("len(Entries) = %d, want %d", len(got.Entries), len(want.Entries))
+	}
+	for i := range want.Entries {
+		if got.Entries[i] != want.Entries[i] {
+			t.Errorf("Entries[%d] = %+v, want %+v", i, got.Entries[i], want.Entries[i])
+		}
+	}
+	if len(got.Skipped) != 1 || got.Skipped[0] != want.Skipped[0] {
+		t.Errorf("Skipped = %+v, want %+v", got.Skipped, want.Skipped)
+	}
+}
+
+func TestLoadInstallStateMissingFile(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(nil)
+	if err != nil {
+		t.Fatalf("creating test fs: %v", err)
+	}
+	defer cleanup()
+
+	if _, err := LoadInstallState(fs, "/does-not-exist.yaml"); err == nil {
+		t.Fatal("LoadInstallState() expected error for missing file")
+	}
+}
+
+func TestLoadInstallStateInvalidYAML(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/state.yaml": "not: valid: yaml: [",
+	})
+	if err != nil {
+		t.Fatalf("creating test fs: %v", err)
+	}
+	defer cleanup()
+
+	if _, err := LoadInstallState(fs, "/state.yaml"); err == nil {
+		t.Fatal("LoadInstallState() expected error for invalid yaml")
+	}
+}
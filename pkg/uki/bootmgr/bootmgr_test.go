@@ -0,0 +1,144 @@
+package bootmgr
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// efivarsFixture is the path to an OVMF NVRAM image (a copy of
+// OVMF_VARS.fd, see testdata/README.md) for exercising CreateEntry and
+// SetBootOrder against a real, if virtual, UEFI NVRAM under qemu by hand.
+// No test in this file uses it: they stub Manager.runner instead, so they
+// don't need qemu/OVMF to run.
+const efivarsFixture = "testdata/efivars.fd"
+
+const sampleEfibootmgrOutput = `BootCurrent: 0001
+Timeout: 1 seconds
+BootOrder: 0001,0000,0002
+Boot0000* Windows Boot Manager	HD(1,GPT,111,0x800,0x32000)/File(\EFI\Microsoft\Boot\bootmgfw.efi)
+Boot0001* Kairos	HD(1,GPT,111,0x800,0x32000)/File(\EFI\BOOT\BOOTX64.EFI)
+Boot0002  UEFI OS	HD(1,GPT,111,0x800,0x32000)/File(\EFI\BOOT\BOOTX64.EFI)
+`
+
+func newStubManager(t *testing.T, runner func(args ...string) (string, error)) *Manager {
+	t.Helper()
+	return &Manager{runner: runner}
+}
+
+func TestListEntries(t *testing.T) {
+	m := newStubManager(t, func(args ...string) (string, error) {
+		return sampleEfibootmgrOutput, nil
+	})
+
+	entries, err := m.ListEntries()
+	if err != nil {
+		t.Fatalf("ListEntries() error = %v", err)
+	}
+
+	want := []Entry{
+		{Num: "0000", Active: true, Description: "Windows Boot Manager"},
+		{Num: "0001", Active: true, Description: "Kairos"},
+		{Num: "0002", Active: false, Description: "UEFI OS"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ListEntries() = %#v, want %#v", entries, want)
+	}
+}
+
+func TestPurgeEntriesForDescription(t *testing.T) {
+	var deleted []string
+	m := newStubManager(t, func(args ...string) (string, error) {
+		if len(args) == 0 {
+			return sampleEfibootmgrOutput, nil
+		}
+		if args[0] == "--bootnum" {
+			deleted = append(deleted, args[1])
+		}
+		return "", nil
+	})
+
+	if err := m.PurgeEntriesForDescription("Kairos"); err != nil {
+		t.Fatalf("PurgeEntriesForDescription() error = %v", err)
+	}
+
+	want := []string{"0001"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted entries = %v, want %v", deleted, want)
+	}
+}
+
+func TestBootOrder(t *testing.T) {
+	m := newStubManager(t, func(args ...string) (string, error) {
+		return sampleEfibootmgrOutput, nil
+	})
+
+	order, err := m.BootOrder()
+	if err != nil {
+		t.Fatalf("BootOrder() error = %v", err)
+	}
+
+	want := []string{"0001", "0000", "0002"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("BootOrder() = %v, want %v", order, want)
+	}
+}
+
+func TestSetBootOrderPreservesExistingEntries(t *testing.T) {
+	var got string
+	m := newStubManager(t, func(args ...string) (string, error) {
+		for i, a := range args {
+			if a == "--bootorder" {
+				got = args[i+1]
+			}
+		}
+		return "", nil
+	})
+
+	if err := m.SetBootOrder("0003", "0001", "0000"); err != nil {
+		t.Fatalf("SetBootOrder() error = %v", err)
+	}
+
+	if want := "0003,0001,0000"; got != want {
+		t.Errorf("--bootorder = %q, want %q", got, want)
+	}
+}
+
+func TestCreateEntryFindsItsOwnEntry(t *testing.T) {
+	m := newStubManager(t, func(args ...string) (string, error) {
+		return sampleEfibootmgrOutput, nil
+	})
+
+	num, err := m.CreateEntry(CreateOptions{
+		Description: "Kairos",
+		Disk:        "/dev/sda",
+		Part:        1,
+		Loader:      `\EFI\BOOT\BOOTX64.EFI`,
+	})
+	if err != nil {
+		t.Fatalf("CreateEntry() error = %v", err)
+	}
+	if num != "0001" {
+		t.Errorf("CreateEntry() = %q, want %q", num, "0001")
+	}
+}
+
+func TestCreateEntryNoEntriesIsError(t *testing.T) {
+	m := newStubManager(t, func(args ...string) (string, error) {
+		return "", nil
+	})
+
+	if _, err := m.CreateEntry(CreateOptions{Description: "Kairos"}); err == nil {
+		t.Fatal("CreateEntry() expected error when efibootmgr reports no entries")
+	}
+}
+
+func TestListEntriesPropagatesRunnerError(t *testing.T) {
+	m := NewManager()
+	m.runner = func(args ...string) (string, error) {
+		return "", fmt.Errorf("exit status 1")
+	}
+	if _, err := m.ListEntries(); err == nil {
+		t.Fatal("ListEntries() expected error to be propagated")
+	}
+}
@@ -0,0 +1,29 @@
+package cloudinit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpClient bounds how long a single attempt at fetching a remote
+// cloud-init source may take, so an endpoint that accepts the connection
+// but never responds can't hang the install indefinitely.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// resolveHTTP fetches ref over plain HTTP(S). Retries are handled by the
+// caller via withRetry.
+func (r *Resolver) resolveHTTP(ref string) ([]byte, error) {
+	resp, err := httpClient.Get(ref) //nolint:gosec // ref comes from the cloud-config the operator wrote
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
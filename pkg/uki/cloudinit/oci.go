@@ -0,0 +1,71 @@
+package cloudinit
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// resolveOCI fetches an oci:// cloud-init reference and extracts its
+// single-file payload. ref is the registry reference with the "oci://"
+// prefix and any `#sha256=...` checksum fragment already stripped by the
+// caller (e.g. "registry.example.com/kairos/cloud-config:latest").
+//
+// The artifact is expected to carry exactly one layer, itself a tar archive
+// containing exactly one regular file (the cloud-config document) — the same
+// convention used for OCI-packaged cloud-init elsewhere in the Kairos
+// ecosystem. Any other shape is rejected rather than guessed at.
+func (r *Resolver) resolveOCI(ref string) ([]byte, error) {
+	img, err := crane.Pull(ref)
+	if err != nil {
+		return nil, fmt.Errorf("pulling oci:// reference %q: %w", ref, err)
+	}
+	return extractSingleFileLayer(img)
+}
+
+func extractSingleFileLayer(img v1.Image) ([]byte, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("reading image layers: %w", err)
+	}
+	if len(layers) != 1 {
+		return nil, fmt.Errorf("expected exactly one layer, got %d", len(layers))
+	}
+
+	rc, err := layers[0].Uncompressed()
+	if err != nil {
+		return nil, fmt.Errorf("decompressing layer: %w", err)
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	var data []byte
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading layer tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if found {
+			return nil, fmt.Errorf("expected exactly one file in layer, found a second: %s", hdr.Name)
+		}
+		data, err = io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from layer tar: %w", hdr.Name, err)
+		}
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("layer tar contained no regular file")
+	}
+	return data, nil
+}
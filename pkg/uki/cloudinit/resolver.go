@@ -0,0 +1,123 @@
+// Package cloudinit resolves InstallUkiSpec.CloudInit entries into their
+// materialized bytes, which the UKI install writes to local files for
+// elemental.Elemental.CopyCloudConfig, supporting local paths as well as
+// file://, http(s)://, tpm:// and oci:// references.
+package cloudinit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Resolver materializes a CloudInit entry into bytes, verifying an optional
+// checksum and retrying transient failures for remote sources.
+type Resolver struct {
+	// Retries is the number of additional attempts made for remote sources
+	// after the first failure.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles after each
+	// subsequent attempt.
+	Backoff time.Duration
+	// sleep is overridable in tests so retry/backoff doesn't actually wait.
+	sleep func(time.Duration)
+}
+
+// NewResolver returns a Resolver with the default retry/backoff policy.
+func NewResolver() *Resolver {
+	return &Resolver{Retries: 3, Backoff: time.Second, sleep: time.Sleep}
+}
+
+// Resolve fetches and verifies the CloudInit entry ref, returning its
+// materialized contents. ref can be a bare local path (treated the same as
+// file://) or a file://, http(s)://, tpm:// or oci:// URI. A `#sha256=<hex>`
+// fragment on any scheme is verified against the fetched bytes.
+func (r *Resolver) Resolve(ref string) ([]byte, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme == "" {
+		// Bare local paths (the only form CopyCloudConfig understood before)
+		// are treated as file:// references. Use the parsed path, if any, so
+		// a trailing `#sha256=...` fragment isn't taken as part of the path.
+		path := ref
+		if err == nil {
+			path = u.Path
+		}
+		data, ferr := r.resolveFile(path)
+		return r.verify(u, data, ferr)
+	}
+
+	var data []byte
+	switch u.Scheme {
+	case "file":
+		data, err = r.resolveFile(u.Path)
+	case "http", "https":
+		data, err = r.withRetry(func() ([]byte, error) { return r.resolveHTTP(ref) })
+	case "tpm":
+		data, err = r.resolveTPM(u)
+	case "oci":
+		ociRef := strings.TrimPrefix(ref, "oci://")
+		if u.Fragment != "" {
+			ociRef = strings.TrimSuffix(ociRef, "#"+u.Fragment)
+		}
+		data, err = r.withRetry(func() ([]byte, error) { return r.resolveOCI(ociRef) })
+	default:
+		return nil, fmt.Errorf("unsupported cloud-init source scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolving cloud-init source %q: %w", ref, err)
+	}
+	return r.verify(u, data, nil)
+}
+
+func (r *Resolver) resolveFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// withRetry calls fetch up to 1+r.Retries times, backing off exponentially
+// starting at r.Backoff between attempts.
+func (r *Resolver) withRetry(fetch func() ([]byte, error)) ([]byte, error) {
+	backoff := r.Backoff
+	var lastErr error
+	for attempt := 0; attempt <= r.Retries; attempt++ {
+		data, err := fetch()
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if attempt == r.Retries {
+			break
+		}
+		if r.sleep != nil {
+			r.sleep(backoff)
+		}
+		backoff *= 2
+	}
+	return nil, lastErr
+}
+
+// verify checks the `sha256` fragment on u (if any) against data, and
+// returns data unchanged when it matches (or when no checksum was given).
+func (r *Resolver) verify(u *url.URL, data []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Fragment == "" {
+		return data, nil
+	}
+
+	expected, ok := strings.CutPrefix(u.Fragment, "sha256=")
+	if !ok {
+		return data, nil
+	}
+
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(got, expected) {
+		return nil, fmt.Errorf("checksum mismatch: expected sha256:%s, got sha256:%s", expected, got)
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+package cloudinit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestResolver() *Resolver {
+	r := NewResolver()
+	r.Retries = 2
+	r.Backoff = time.Millisecond
+	r.sleep = func(time.Duration) {}
+	return r
+}
+
+func TestResolveBarePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "userdata.yaml")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := newTestResolver().Resolve(path)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("Resolve() = %q, want %q", data, "hello")
+	}
+}
+
+func TestResolveBarePathChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "userdata.yaml")
+	content := []byte("hello")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+
+	t.Run("match", func(t *testing.T) {
+		ref := path + "#sha256=" + hex.EncodeToString(sum[:])
+		if _, err := newTestResolver().Resolve(ref); err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		ref := path + "#sha256=" + hex.EncodeToString(make([]byte, 32))
+		if _, err := newTestResolver().Resolve(ref); err == nil {
+			t.Fatal("Resolve() expected checksum mismatch error")
+		}
+	})
+}
+
+func TestResolveFileScheme(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "userdata.yaml")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := newTestResolver().Resolve("file://" + path)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("Resolve() = %q, want %q", data, "hello")
+	}
+}
+
+func TestResolveUnsupportedScheme(t *testing.T) {
+	if _, err := newTestResolver().Resolve("ftp://example.com/userdata.yaml"); err == nil {
+		t.Fatal("Resolve() expected error for unsupported scheme")
+	}
+}
+
+func TestResolveHTTPRetriesThenSucceeds(t *testing.T) {
+	r := newTestResolver()
+	attempts := 0
+	data, err := r.withRetry(func() ([]byte, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("transient")
+		}
+		return []byte("ok"), nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry() error = %v", err)
+	}
+	if string(data) != "ok" {
+		t.Errorf("withRetry() = %q, want %q", data, "ok")
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestResolveHTTPRetriesExhausted(t *testing.T) {
+	r := newTestResolver()
+	attempts := 0
+	_, err := r.withRetry(func() ([]byte, error) {
+		attempts++
+		return nil, errors.New("permanent")
+	})
+	if err == nil {
+		t.Fatal("withRetry() expected error after exhausting retries")
+	}
+	if want := r.Retries + 1; attempts != want {
+		t.Errorf("attempts = %d, want %d", attempts, want)
+	}
+}
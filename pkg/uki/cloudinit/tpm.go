@@ -0,0 +1,97 @@
+package cloudinit
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/transport"
+	"github.com/google/go-tpm/tpm2/transport/linuxtpm"
+)
+
+// TPMDevice is the TPM character device used to unseal tpm:// cloud-init
+// references. Overridable in tests/non-standard setups.
+var TPMDevice = "/dev/tpmrm0"
+
+// sealedPCRSelection is the PCR set a tpm:// cloud-config blob must be sealed
+// against: 7 (secure boot state) and 11 (UKI measurement, per
+// systemd-stub/systemd-pcrphase convention). Together they guarantee the
+// config is only readable by the exact measured UKI it was sealed for.
+var sealedPCRSelection = tpm2.TPMLPCRSelection{
+	PCRSelections: []tpm2.TPMSPCRSelection{
+		{
+			Hash:      tpm2.TPMAlgSHA256,
+			PCRSelect: tpm2.PCClientCompatible.PCRs(7, 11),
+		},
+	},
+}
+
+// resolveTPM unseals the cloud-config blob stored at the NV index referenced
+// by u (e.g. "tpm://0x1500020"), gated by a policy session over
+// sealedPCRSelection so the TPM itself refuses the read unless PCR 7 and 11
+// still match what the blob was sealed against.
+func (r *Resolver) resolveTPM(u *url.URL) ([]byte, error) {
+	nvIndexStr := strings.TrimPrefix(u.Host+u.Path, "/")
+	nvIndex, err := strconv.ParseUint(strings.TrimPrefix(nvIndexStr, "0x"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parsing tpm:// NV index %q: %w", nvIndexStr, err)
+	}
+
+	rwc, err := linuxtpm.Open(TPMDevice)
+	if err != nil {
+		return nil, fmt.Errorf("opening TPM device %s: %w", TPMDevice, err)
+	}
+	defer rwc.Close()
+
+	data, err := readSealedNV(rwc, tpm2.TPMHandle(nvIndex))
+	if err != nil {
+		return nil, fmt.Errorf("reading sealed cloud-config from NV index 0x%x: %w", nvIndex, err)
+	}
+	return data, nil
+}
+
+// readSealedNV reads the full contents of the NV index at handle, authorized
+// by a policy session that must satisfy sealedPCRSelection.
+func readSealedNV(rwc transport.TPMCloser, handle tpm2.TPMHandle) ([]byte, error) {
+	session, cleanup, err := tpm2.PolicySession(rwc, tpm2.TPMAlgSHA256, 16)
+	if err != nil {
+		return nil, fmt.Errorf("starting TPM policy session: %w", err)
+	}
+	defer cleanup()
+
+	if _, err := (tpm2.PolicyPCR{
+		PolicySession: session.Handle(),
+		Pcrs:          sealedPCRSelection,
+	}).Execute(rwc); err != nil {
+		return nil, fmt.Errorf("satisfying PCR policy: %w", err)
+	}
+
+	readPub, err := (tpm2.NVReadPublic{NVIndex: handle}).Execute(rwc)
+	if err != nil {
+		return nil, fmt.Errorf("reading NV index public area: %w", err)
+	}
+	pub, err := readPub.NVPublic.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling NV index public area: %w", err)
+	}
+
+	read := tpm2.NVRead{
+		AuthHandle: tpm2.AuthHandle{
+			Handle: handle,
+			Auth:   session,
+		},
+		NVIndex: tpm2.NamedHandle{
+			Handle: handle,
+			Name:   readPub.NVName,
+		},
+		Size:   pub.DataSize,
+		Offset: 0,
+	}
+	readRsp, err := read.Execute(rwc)
+	if err != nil {
+		return nil, fmt.Errorf("reading NV index data: %w", err)
+	}
+	return readRsp.Data.Buffer, nil
+}
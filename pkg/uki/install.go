@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	hook "github.com/kairos-io/kairos-agent/v2/internal/agent/hooks"
 	"github.com/kairos-io/kairos-agent/v2/pkg/config"
 	"github.com/kairos-io/kairos-agent/v2/pkg/constants"
 	"github.com/kairos-io/kairos-agent/v2/pkg/elemental"
 	v1 "github.com/kairos-io/kairos-agent/v2/pkg/types/v1"
+	"github.com/kairos-io/kairos-agent/v2/pkg/uki/bootmgr"
+	"github.com/kairos-io/kairos-agent/v2/pkg/uki/cloudinit"
+	"github.com/kairos-io/kairos-agent/v2/pkg/uki/progress"
 	"github.com/kairos-io/kairos-agent/v2/pkg/utils"
 	fsutils "github.com/kairos-io/kairos-agent/v2/pkg/utils/fs"
 	events "github.com/kairos-io/kairos-sdk/bus"
@@ -19,12 +25,52 @@ import (
 )
 
 type InstallAction struct {
-	cfg  *config.Config
-	spec *v1.InstallUkiSpec
+	cfg      *config.Config
+	spec     *v1.InstallUkiSpec
+	progress progress.Sink
 }
 
-func NewInstallAction(cfg *config.Config, spec *v1.InstallUkiSpec) *InstallAction {
-	return &InstallAction{cfg: cfg, spec: spec}
+// InstallOption customizes an InstallAction at construction time.
+type InstallOption func(*InstallAction)
+
+// WithProgress overrides the Sink used to report install phase progress.
+// Defaults to a Sink that forwards to cfg.Logger, matching the previous
+// Debugf/Infof-only behavior.
+func WithProgress(sink progress.Sink) InstallOption {
+	return func(i *InstallAction) {
+		i.progress = sink
+	}
+}
+
+// ukiVariantRegexp matches the `kairos-<tag>.efi`/`.conf` naming convention
+// enki uses to ship several cmdline variants of the same artifact next to
+// each other (e.g. kairos-recovery.efi, kairos-passive.conf).
+var ukiVariantRegexp = regexp.MustCompile(`^kairos-([^.]+)\.(efi|conf)$`)
+
+// uniqueCmdlineRoles is the role assigned to each successive unique cmdline
+// found when InstallUkiSpec.CmdlineMode is CmdlineModeUniqueCmdline.
+var uniqueCmdlineRoles = []string{"active", "passive", "recovery"}
+
+// entryTag reports whether filename belongs to the EFI source being
+// installed, and if so, which cmdline-variant tag it carries. The legacy
+// single-variant convention (a literal "artifact" placeholder in the name)
+// reports the empty tag so it keeps being handled exactly as before.
+func entryTag(filename string) (tag string, ok bool) {
+	if strings.Contains(filename, "artifact") {
+		return "", true
+	}
+	if m := ukiVariantRegexp.FindStringSubmatch(filename); m != nil {
+		return m[1], true
+	}
+	return "", false
+}
+
+func NewInstallAction(cfg *config.Config, spec *v1.InstallUkiSpec, opts ...InstallOption) *InstallAction {
+	i := &InstallAction{cfg: cfg, spec: spec, progress: progress.NewLoggerSink(cfg.Logger)}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
 }
 
 func (i *InstallAction) Run() (err error) {
@@ -40,13 +86,21 @@ func (i *InstallAction) Run() (err error) {
 	if err != nil {
 		return err
 	}
+	if err = i.ensureEFISize(); err != nil {
+		return err
+	}
+
 	// Partition device
+	i.progress.Start(progress.PhasePartition, 0)
 	err = e.PartitionAndFormatDevice(i.spec)
+	i.progress.Finish(progress.PhasePartition, err)
 	if err != nil {
 		return err
 	}
 
+	i.progress.Start(progress.PhaseMount, 0)
 	err = e.MountPartitions(i.spec.GetPartitions().PartitionsByMountPoint(false))
+	i.progress.Finish(progress.PhaseMount, err)
 	if err != nil {
 		return err
 	}
@@ -60,9 +114,15 @@ func (i *InstallAction) Run() (err error) {
 		return err
 	}
 
-	// Store cloud-config in TPM or copy it to COS_OEM?
-	// Copy cloud-init if any
-	err = e.CopyCloudConfig(i.spec.CloudInit)
+	// Copy cloud-init if any. Entries may be local paths or file://, http(s)://,
+	// tpm:// and oci:// references; resolve them to local paths first.
+	cloudInitPaths, err := i.resolveCloudInit(cleanup)
+	if err != nil {
+		return err
+	}
+	i.progress.Start(progress.PhaseCloudInit, int64(len(cloudInitPaths)))
+	err = e.CopyCloudConfig(cloudInitPaths)
+	i.progress.Finish(progress.PhaseCloudInit, err)
 	if err != nil {
 		return err
 	}
@@ -75,15 +135,30 @@ func (i *InstallAction) Run() (err error) {
 		return err
 	}
 
-	// Copy the efi file into the proper dir
-	_, err = e.DumpSource(i.spec.Partitions.EFI.MountPoint, i.spec.Active.Source)
+	// Copy the efi file into the proper dir. DumpSource has no progress
+	// callback of its own, so poll the destination's growing size on the
+	// side to give the sink real interim updates instead of a single
+	// bookend call after the copy has already finished.
+	i.progress.Start(progress.PhaseDumpSource, int64(i.spec.Active.Source.SizeMiB)*1024*1024)
+	stopPolling := i.pollDumpSourceProgress(i.spec.Partitions.EFI.MountPoint)
+	written, err := e.DumpSource(i.spec.Partitions.EFI.MountPoint, i.spec.Active.Source)
+	stopPolling()
+	if err == nil {
+		i.progress.Update(progress.PhaseDumpSource, written)
+	}
+	i.progress.Finish(progress.PhaseDumpSource, err)
 	if err != nil {
 		return err
 	}
 
 	// Remove entries
 	// Read all confs
-	i.cfg.Logger.Debugf("Parsing efi partition files (skip SkipEntries, replace placeholders etc)")
+	var installedEntries []v1.InstallStateEntry
+	var skippedEntries []v1.InstallStateSkip
+	seenCmdlines := map[string]bool{}
+	defaultConfName := "active"
+	i.progress.Start(progress.PhaseConfEntry, 0)
+	var processed int64
 	err = fsutils.WalkDirFs(i.cfg.Fs, filepath.Join(i.spec.Partitions.EFI.MountPoint), func(path string, info os.DirEntry, err error) error {
 		filename := info.Name()
 		if err != nil {
@@ -95,6 +170,8 @@ func (i *InstallAction) Run() (err error) {
 		if info.IsDir() {
 			return nil
 		}
+		processed++
+		i.progress.Update(progress.PhaseConfEntry, processed)
 
 		if filepath.Ext(filename) == ".conf" {
 			// Extract the values
@@ -117,46 +194,332 @@ func (i *InstallAction) Run() (err error) {
 				}
 			}
 			if skip {
+				skippedEntries = append(skippedEntries, v1.InstallStateSkip{Cmdline: conf["cmdline"], Reason: "matched SkipEntries"})
 				return i.SkipEntry(path, conf)
 			}
 
-			if !strings.Contains(filename, "artifact") {
+			tag, isVariant := entryTag(filename)
+			if !isVariant {
 				return nil
 			}
 
-			if err := i.replacePlaceholders(path, "efi", "active"); err != nil {
-				return fmt.Errorf("replacing placeholders in %s: %w", filename, err)
+			role, install := i.resolveCmdlineRole(tag, conf["cmdline"], seenCmdlines)
+			if !install {
+				i.cfg.Logger.Debugf("Cmdline mode %q excludes %s (tag %q)", i.spec.CmdlineMode, path, tag)
+				skippedEntries = append(skippedEntries, v1.InstallStateSkip{Cmdline: conf["cmdline"], Reason: fmt.Sprintf("excluded by cmdline mode %q", i.spec.CmdlineMode)})
+				return i.SkipEntry(path, conf)
+			}
+
+			// The legacy single-variant convention still relies on the
+			// "artifact" placeholder being replaced everywhere; tagged
+			// variants already carry their final name on disk.
+			installedFilename := filename
+			if tag == "" {
+				if err := i.replacePlaceholders(path, "efi", "active"); err != nil {
+					return fmt.Errorf("replacing placeholders in %s: %w", filename, err)
+				}
+				installedFilename = strings.ReplaceAll(filename, "artifact", "active")
 			}
 
-			return i.replaceFilenamePlaceholder(path, "active")
-		} else if filepath.Ext(filename) == ".efi" && strings.Contains(filename, "artifact") {
-			return i.replaceFilenamePlaceholder(path, "active")
+			isDefault := (i.spec.DefaultCmdlineTag != "" && tag == i.spec.DefaultCmdlineTag) || (i.spec.DefaultCmdlineTag == "" && role == "active")
+			if isDefault {
+				defaultConfName = strings.TrimSuffix(installedFilename, filepath.Ext(installedFilename))
+			}
+
+			installedEntries = append(installedEntries, v1.InstallStateEntry{
+				File:    installedFilename,
+				Cmdline: conf["cmdline"],
+				Role:    role,
+				Default: isDefault,
+			})
+
+			if tag == "" {
+				return i.replaceFilenamePlaceholder(path, "active")
+			}
+			return nil
+		} else if filepath.Ext(filename) == ".efi" {
+			tag, isVariant := entryTag(filename)
+			if !isVariant {
+				return nil
+			}
+			if tag == "" {
+				return i.replaceFilenamePlaceholder(path, "active")
+			}
 		}
 
 		return nil
 	})
+	i.progress.Finish(progress.PhaseConfEntry, err)
 	if err != nil {
 		return err
 	}
 
+	i.progress.Start(progress.PhaseLoaderConf, 0)
 	loaderConfPath := filepath.Join(i.spec.Partitions.EFI.MountPoint, "loader", "loader.conf")
-	if err = i.replacePlaceholders(loaderConfPath, "default", "active"); err != nil {
+	err = i.replacePlaceholders(loaderConfPath, "default", defaultConfName)
+	i.progress.Finish(progress.PhaseLoaderConf, err)
+	if err != nil {
+		return err
+	}
+
+	if err = i.writeInstallState(installedEntries, skippedEntries); err != nil {
 		return err
 	}
 
+	i.progress.Start(progress.PhaseHooks, 0)
 	// after install hook happens after install (this is for compatibility with normal install, so users can reuse their configs)
 	err = Hook(i.cfg, constants.AfterInstallHook)
 	if err != nil {
+		i.progress.Finish(progress.PhaseHooks, err)
 		return err
 	}
-	// Remove all boot manager entries?
-	// Create boot manager entry
-	// Set default entry to the one we just created
+
+	if !i.spec.SkipBootManager {
+		if err = i.registerBootManagerEntry(); err != nil {
+			i.progress.Finish(progress.PhaseHooks, err)
+			return err
+		}
+	} else {
+		i.progress.Event("debug", "SkipBootManager is set, not touching UEFI boot manager entries")
+	}
 	// Probably copy efi utils, like the Mokmanager and even the shim or grub efi to help with troubleshooting?
 	_ = utils.RunStage(i.cfg, "kairos-uki-install.after")
 	_ = events.RunHookScript("/usr/bin/kairos-agent.uki.install.after.hook") //nolint:errcheck
 
-	return hook.Run(*i.cfg, i.spec, hook.AfterUkiInstall...)
+	err = hook.Run(*i.cfg, i.spec, hook.AfterUkiInstall...)
+	i.progress.Finish(progress.PhaseHooks, err)
+	return err
+}
+
+// writeInstallState persists a state.yaml describing what was just installed
+// to both the EFI and the OEM/persistent partitions, so that later upgrade
+// and reset flows can introspect which image/entries are currently deployed
+// without re-parsing every .conf file on the EFI partition.
+func (i *InstallAction) writeInstallState(entries []v1.InstallStateEntry, skipped []v1.InstallStateSkip) error {
+	state := &v1.InstallState{
+		Source:  i.spec.Active.Source,
+		Entries: entries,
+		Skipped: skipped,
+	}
+	if i.spec.Partitions.EFI != nil {
+		state.Partitions = append(state.Partitions, v1.InstallStatePart{Label: i.spec.Partitions.EFI.Label, Size: i.spec.Partitions.EFI.Size})
+	}
+	if i.spec.Partitions.Persistent != nil {
+		state.Partitions = append(state.Partitions, v1.InstallStatePart{Label: i.spec.Partitions.Persistent.Label, Size: i.spec.Partitions.Persistent.Size})
+	}
+
+	efiStatePath := filepath.Join(i.spec.Partitions.EFI.MountPoint, v1.InstallStateFile)
+	i.cfg.Logger.Debugf("Writing install state to %s", efiStatePath)
+	if err := v1.WriteInstallState(i.cfg.Fs, state, efiStatePath); err != nil {
+		return fmt.Errorf("writing install state to EFI partition: %w", err)
+	}
+
+	if i.spec.Partitions.Persistent != nil {
+		oemStatePath := filepath.Join(i.spec.Partitions.Persistent.MountPoint, v1.InstallStateFile)
+		i.cfg.Logger.Debugf("Writing install state to %s", oemStatePath)
+		if err := v1.WriteInstallState(i.cfg.Fs, state, oemStatePath); err != nil {
+			return fmt.Errorf("writing install state to OEM partition: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resolveCloudInit materializes every InstallUkiSpec.CloudInit entry into a
+// local path CopyCloudConfig can read. Every entry — bare local paths
+// included — goes through cloudinit.Resolver (with retry/backoff and
+// checksum verification, see pkg/uki/cloudinit) and is written to a temp
+// file, which is registered with cleanup for removal once the install
+// finishes.
+func (i *InstallAction) resolveCloudInit(cleanup *utils.CleanStack) ([]string, error) {
+	resolver := cloudinit.NewResolver()
+	paths := make([]string, 0, len(i.spec.CloudInit))
+
+	for _, ref := range i.spec.CloudInit {
+		// Always go through the resolver, even for schemeless/bare local
+		// paths: Resolver.Resolve treats those as file:// and still honors a
+		// trailing `#sha256=...` fragment, which a short-circuit here would
+		// otherwise pass through literally as part of the path.
+		data, err := resolver.Resolve(ref)
+		if err != nil {
+			return nil, fmt.Errorf("resolving cloud-init source %q: %w", ref, err)
+		}
+
+		tmp, err := os.CreateTemp("", "kairos-cloud-init-*.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp file for cloud-init source %q: %w", ref, err)
+		}
+		if _, err := tmp.Write(data); err != nil {
+			_ = tmp.Close()
+			return nil, fmt.Errorf("writing resolved cloud-init source %q: %w", ref, err)
+		}
+		_ = tmp.Close()
+
+		tmpPath := tmp.Name()
+		cleanup.Push(func() error { return os.Remove(tmpPath) })
+		paths = append(paths, tmpPath)
+	}
+
+	return paths, nil
+}
+
+// ensureEFISize resolves the EFI partition size to use (InstallUkiSpec.EFISize,
+// falling back to the install.partitions.bootloader.size cloud-config key,
+// and finally to v1.DefaultEFISizeMiB), validates it against the known size
+// of i.spec.Active.Source, and stores the resolved size back onto the EFI
+// partition so PartitionAndFormatDevice picks it up. It fails fast with a
+// clear error instead of letting the install run out of space mid-copy.
+func (i *InstallAction) ensureEFISize() error {
+	if i.spec.EFISize == 0 {
+		if err := config.ApplyUkiPartitions(i.cfg, i.spec); err != nil {
+			return fmt.Errorf("reading install.partitions.bootloader.size from cloud-config: %w", err)
+		}
+	}
+
+	size := i.spec.EFISize
+	if size == 0 {
+		size = v1.DefaultEFISizeMiB
+	}
+
+	required := i.spec.Active.Source.SizeMiB
+	if required > 0 {
+		artifacts := i.spec.Active.Source.Artifacts
+		if artifacts == 0 {
+			artifacts = 1
+		}
+		if size < required {
+			return fmt.Errorf("EFI partition size %d MiB < required %d MiB for %d artifacts", size, required, artifacts)
+		}
+	}
+
+	if i.spec.Partitions.EFI != nil {
+		i.spec.Partitions.EFI.Size = size
+	}
+
+	return nil
+}
+
+// pollDumpSourceProgress periodically reports the accumulated size of path
+// as PhaseDumpSource progress while DumpSource copies into it. The returned
+// func stops the poller and blocks until it has, so the caller can safely
+// report the final, authoritative byte count immediately afterwards without
+// racing the poller's own progress.Update calls.
+func (i *InstallAction) pollDumpSourceProgress(path string) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if size, err := i.dirSize(path); err == nil {
+					i.progress.Update(progress.PhaseDumpSource, size)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// dirSize returns the combined size in bytes of every regular file under
+// path.
+func (i *InstallAction) dirSize(path string) (int64, error) {
+	var size int64
+	err := fsutils.WalkDirFs(i.cfg.Fs, path, func(_ string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		fi, err := info.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	return size, err
+}
+
+// registerBootManagerEntry purges every existing UEFI boot manager entry
+// labelled "Kairos", whichever disk it points at, creates a fresh one
+// pointing at the EFI binary we just installed, and puts it first in
+// BootOrder so it actually boots instead of relying on the /EFI/BOOT
+// fallback path.
+func (i *InstallAction) registerBootManagerEntry() error {
+	const description = "Kairos"
+
+	mgr := bootmgr.NewManager()
+	if err := mgr.PurgeEntriesForDescription(description); err != nil {
+		return fmt.Errorf("purging stale boot manager entries: %w", err)
+	}
+
+	num, err := mgr.CreateEntry(bootmgr.CreateOptions{
+		Description: description,
+		Disk:        i.spec.Partitions.EFI.Disk,
+		Part:        i.spec.Partitions.EFI.PartNum,
+		Loader:      `\EFI\BOOT\BOOTX64.EFI`,
+	})
+	if err != nil {
+		return fmt.Errorf("creating boot manager entry: %w", err)
+	}
+
+	existingOrder, err := mgr.BootOrder()
+	if err != nil {
+		return fmt.Errorf("reading existing boot order: %w", err)
+	}
+	rest := make([]string, 0, len(existingOrder))
+	for _, entry := range existingOrder {
+		if entry != num {
+			rest = append(rest, entry)
+		}
+	}
+	if err := mgr.SetBootOrder(num, rest...); err != nil {
+		return fmt.Errorf("setting boot order: %w", err)
+	}
+
+	return nil
+}
+
+// resolveCmdlineRole decides, for the entry identified by tag/cmdline,
+// whether it should be installed under the current InstallUkiSpec.CmdlineMode
+// and which role it should be recorded under in the install state. seen
+// tracks cmdlines already accepted so far during this walk, and is mutated
+// when the entry is accepted.
+func (i *InstallAction) resolveCmdlineRole(tag, cmdline string, seen map[string]bool) (role string, install bool) {
+	switch i.spec.CmdlineMode {
+	case v1.CmdlineModeAllowList:
+		if tag != "" && !slices.Contains(i.spec.CmdlineAllowList, tag) {
+			return "", false
+		}
+		if tag == "" {
+			return "active", true
+		}
+		return tag, true
+	case v1.CmdlineModeUniqueCmdline:
+		if seen[cmdline] {
+			return "", false
+		}
+		seen[cmdline] = true
+		role = "extra"
+		if idx := len(seen) - 1; idx < len(uniqueCmdlineRoles) {
+			role = uniqueCmdlineRoles[idx]
+		}
+		return role, true
+	default: // v1.CmdlineModeAll and unset
+		if tag == "" {
+			return "active", true
+		}
+		return tag, true
+	}
 }
 
 func (i *InstallAction) SkipEntry(path string, conf map[string]string) (err error) {
@@ -0,0 +1,174 @@
+package uki
+
+import (
+	"testing"
+
+	"github.com/kairos-io/kairos-agent/v2/pkg/config"
+	v1 "github.com/kairos-io/kairos-agent/v2/pkg/types/v1"
+)
+
+func TestResolveCmdlineRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         v1.CmdlineMode
+		allowList    []string
+		tag          string
+		cmdline      string
+		seen         map[string]bool
+		wantRole     string
+		wantInstall  bool
+		wantSeenSize int
+	}{
+		{
+			name:        "default mode, untagged legacy entry",
+			mode:        v1.CmdlineModeAll,
+			tag:         "",
+			wantRole:    "active",
+			wantInstall: true,
+		},
+		{
+			name:        "default mode, tagged entry keeps its own role",
+			mode:        v1.CmdlineModeAll,
+			tag:         "passive",
+			wantRole:    "passive",
+			wantInstall: true,
+		},
+		{
+			name:        "unset mode behaves like all",
+			mode:        "",
+			tag:         "recovery",
+			wantRole:    "recovery",
+			wantInstall: true,
+		},
+		{
+			name:        "allow-list mode, untagged legacy entry",
+			mode:        v1.CmdlineModeAllowList,
+			tag:         "",
+			wantRole:    "active",
+			wantInstall: true,
+		},
+		{
+			name:        "allow-list mode, allowed tag",
+			mode:        v1.CmdlineModeAllowList,
+			allowList:   []string{"passive"},
+			tag:         "passive",
+			wantRole:    "passive",
+			wantInstall: true,
+		},
+		{
+			name:        "allow-list mode, disallowed tag is excluded",
+			mode:        v1.CmdlineModeAllowList,
+			allowList:   []string{"passive"},
+			tag:         "recovery",
+			wantRole:    "",
+			wantInstall: false,
+		},
+		{
+			name:         "unique-cmdline mode, first cmdline becomes active",
+			mode:         v1.CmdlineModeUniqueCmdline,
+			cmdline:      "root=LABEL=COS_ACTIVE",
+			seen:         map[string]bool{},
+			wantRole:     "active",
+			wantInstall:  true,
+			wantSeenSize: 1,
+		},
+		{
+			name:         "unique-cmdline mode, duplicate cmdline is excluded",
+			mode:         v1.CmdlineModeUniqueCmdline,
+			cmdline:      "root=LABEL=COS_ACTIVE",
+			seen:         map[string]bool{"root=LABEL=COS_ACTIVE": true},
+			wantRole:     "",
+			wantInstall:  false,
+			wantSeenSize: 1,
+		},
+		{
+			name:         "unique-cmdline mode, fourth distinct cmdline is extra",
+			mode:         v1.CmdlineModeUniqueCmdline,
+			cmdline:      "root=LABEL=COS_EXTRA",
+			seen:         map[string]bool{"a": true, "b": true, "c": true},
+			wantRole:     "extra",
+			wantInstall:  true,
+			wantSeenSize: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := tt.seen
+			if seen == nil {
+				seen = map[string]bool{}
+			}
+			i := &InstallAction{spec: &v1.InstallUkiSpec{CmdlineMode: tt.mode, CmdlineAllowList: tt.allowList}}
+
+			role, install := i.resolveCmdlineRole(tt.tag, tt.cmdline, seen)
+			if role != tt.wantRole {
+				t.Errorf("role = %q, want %q", role, tt.wantRole)
+			}
+			if install != tt.wantInstall {
+				t.Errorf("install = %v, want %v", install, tt.wantInstall)
+			}
+			if tt.wantSeenSize != 0 && len(seen) != tt.wantSeenSize {
+				t.Errorf("len(seen) = %d, want %d", len(seen), tt.wantSeenSize)
+			}
+		})
+	}
+}
+
+func TestResolveCmdlineRoleDefaultModeDoesNotClashAcrossTags(t *testing.T) {
+	i := &InstallAction{spec: &v1.InstallUkiSpec{}}
+	seen := map[string]bool{}
+
+	activeRole, _ := i.resolveCmdlineRole("", "root=LABEL=COS_ACTIVE", seen)
+	passiveRole, _ := i.resolveCmdlineRole("passive", "root=LABEL=COS_PASSIVE", seen)
+	recoveryRole, _ := i.resolveCmdlineRole("recovery", "root=LABEL=COS_RECOVERY", seen)
+
+	if activeRole != "active" {
+		t.Errorf("active entry role = %q, want %q", activeRole, "active")
+	}
+	if passiveRole == activeRole || recoveryRole == activeRole {
+		t.Errorf("tagged entries must not collide with the active role: passive=%q recovery=%q active=%q", passiveRole, recoveryRole, activeRole)
+	}
+}
+
+func TestEnsureEFISizeDefaultsWhenUnset(t *testing.T) {
+	i := &InstallAction{
+		cfg:  &config.Config{},
+		spec: &v1.InstallUkiSpec{Partitions: v1.ElementalPartitions{EFI: &v1.Partition{}}},
+	}
+
+	if err := i.ensureEFISize(); err != nil {
+		t.Fatalf("ensureEFISize() error = %v", err)
+	}
+	if i.spec.Partitions.EFI.Size != v1.DefaultEFISizeMiB {
+		t.Errorf("EFI.Size = %d, want %d", i.spec.Partitions.EFI.Size, v1.DefaultEFISizeMiB)
+	}
+}
+
+func TestEnsureEFISizeHonorsExplicitSize(t *testing.T) {
+	i := &InstallAction{
+		cfg:  &config.Config{},
+		spec: &v1.InstallUkiSpec{EFISize: 256, Partitions: v1.ElementalPartitions{EFI: &v1.Partition{}}},
+	}
+
+	if err := i.ensureEFISize(); err != nil {
+		t.Fatalf("ensureEFISize() error = %v", err)
+	}
+	if i.spec.Partitions.EFI.Size != 256 {
+		t.Errorf("EFI.Size = %d, want 256", i.spec.Partitions.EFI.Size)
+	}
+}
+
+func TestEnsureEFISizeRejectsTooSmallForSource(t *testing.T) {
+	i := &InstallAction{
+		cfg: &config.Config{},
+		spec: &v1.InstallUkiSpec{
+			EFISize:    64,
+			Active:     v1.Image{Source: v1.Source{SizeMiB: 128}},
+			Partitions: v1.ElementalPartitions{EFI: &v1.Partition{}},
+		},
+	}
+
+	if err := i.ensureEFISize(); err == nil {
+		t.Fatal("ensureEFISize() expected error when EFISize is smaller than the source size")
+	}
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonEvent is the wire format written by JSONSink, one per line, for
+// automation (ISO/imager callers) to consume non-interactively.
+type jsonEvent struct {
+	Type  string `json:"type"` // "start", "update", "event", "finish"
+	Phase string `json:"phase,omitempty"`
+	Total int64  `json:"total,omitempty"`
+	Done  int64  `json:"done,omitempty"`
+	Level string `json:"level,omitempty"`
+	Msg   string `json:"msg,omitempty"`
+	Err   string `json:"error,omitempty"`
+}
+
+// JSONSink emits newline-delimited JSON progress events to out, for
+// automation wrapping kairos-agent non-interactively.
+type JSONSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a Sink that writes JSON-lines events to out.
+func NewJSONSink(out io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(out)}
+}
+
+func (s *JSONSink) write(e jsonEvent) {
+	// Encoding errors are not actionable for a progress sink; best effort.
+	_ = s.enc.Encode(e)
+}
+
+func (s *JSONSink) Start(phase string, total int64) {
+	s.write(jsonEvent{Type: "start", Phase: phase, Total: total})
+}
+
+func (s *JSONSink) Update(phase string, done int64) {
+	s.write(jsonEvent{Type: "update", Phase: phase, Done: done})
+}
+
+func (s *JSONSink) Event(level, msg string) {
+	s.write(jsonEvent{Type: "event", Level: level, Msg: msg})
+}
+
+func (s *JSONSink) Finish(phase string, err error) {
+	evt := jsonEvent{Type: "finish", Phase: phase}
+	if err != nil {
+		evt.Err = err.Error()
+	}
+	s.write(evt)
+}
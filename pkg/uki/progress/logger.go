@@ -0,0 +1,46 @@
+package progress
+
+// Logger is the subset of kairos-sdk/types.KairosLogger used by LoggerSink,
+// kept minimal here so this package does not have to import pkg/config.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// LoggerSink is the default Sink: it reports phases through the existing
+// kairos-agent logger, preserving the previous Debugf/Infof-only behavior
+// for callers that do not wire up a dedicated Sink.
+type LoggerSink struct {
+	log Logger
+}
+
+// NewLoggerSink returns a Sink that forwards every report to log.
+func NewLoggerSink(log Logger) *LoggerSink {
+	return &LoggerSink{log: log}
+}
+
+func (s *LoggerSink) Start(phase string, total int64) {
+	s.log.Debugf("[%s] starting (total=%d)", phase, total)
+}
+
+func (s *LoggerSink) Update(phase string, done int64) {
+	s.log.Debugf("[%s] progress: %d", phase, done)
+}
+
+func (s *LoggerSink) Event(level, msg string) {
+	switch level {
+	case "error":
+		s.log.Errorf("%s", msg)
+	default:
+		s.log.Debugf("%s", msg)
+	}
+}
+
+func (s *LoggerSink) Finish(phase string, err error) {
+	if err != nil {
+		s.log.Errorf("[%s] failed: %s", phase, err)
+		return
+	}
+	s.log.Infof("[%s] done", phase)
+}
@@ -0,0 +1,33 @@
+// Package progress reports UKI install phase progress to a pluggable sink,
+// so UIs and automation wrapping kairos-agent (ISO builders, imagers) can
+// display something more useful than raw debug logs during the long
+// DumpSource step.
+package progress
+
+// Sink receives structured progress updates for a sequence of named phases.
+// Implementations need not be safe for concurrent use: InstallAction.Run
+// never overlaps calls. Its DumpSource poller calls Update from a second
+// goroutine, but only between Run's Start and Finish for that phase.
+type Sink interface {
+	// Start marks the beginning of phase. total is the expected unit count
+	// for the phase (e.g. bytes to copy), or 0 if unknown/not applicable.
+	Start(phase string, total int64)
+	// Update reports that done units of the current phase have completed.
+	Update(phase string, done int64)
+	// Event reports a standalone, phase-independent log-like message.
+	// level is one of "debug", "info", "warn", "error".
+	Event(level, msg string)
+	// Finish marks phase as complete. err is nil on success.
+	Finish(phase string, err error)
+}
+
+// Phase names reported by InstallAction.Run.
+const (
+	PhasePartition  = "partition"
+	PhaseMount      = "mount"
+	PhaseCloudInit  = "cloud-config"
+	PhaseDumpSource = "source-dump"
+	PhaseConfEntry  = "conf-entries"
+	PhaseLoaderConf = "placeholder-rewrite"
+	PhaseHooks      = "hooks"
+)
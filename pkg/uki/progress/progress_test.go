@@ -0,0 +1,120 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeLogger struct {
+	debugf []string
+	infof  []string
+	errorf []string
+}
+
+func (f *fakeLogger) Debugf(format string, args ...interface{}) {
+	f.debugf = append(f.debugf, fmt.Sprintf(format, args...))
+}
+func (f *fakeLogger) Infof(format string, args ...interface{}) {
+	f.infof = append(f.infof, fmt.Sprintf(format, args...))
+}
+func (f *fakeLogger) Errorf(format string, args ...interface{}) {
+	f.errorf = append(f.errorf, fmt.Sprintf(format, args...))
+}
+
+func TestLoggerSinkFinishSuccess(t *testing.T) {
+	log := &fakeLogger{}
+	s := NewLoggerSink(log)
+	s.Start(PhasePartition, 0)
+	s.Finish(PhasePartition, nil)
+
+	if len(log.infof) != 1 {
+		t.Fatalf("Infof calls = %d, want 1", len(log.infof))
+	}
+	if len(log.errorf) != 0 {
+		t.Fatalf("Errorf calls = %d, want 0", len(log.errorf))
+	}
+}
+
+func TestLoggerSinkFinishError(t *testing.T) {
+	log := &fakeLogger{}
+	s := NewLoggerSink(log)
+	s.Finish(PhaseMount, errors.New("boom"))
+
+	if len(log.errorf) != 1 {
+		t.Fatalf("Errorf calls = %d, want 1", len(log.errorf))
+	}
+	if !strings.Contains(log.errorf[0], "boom") {
+		t.Errorf("Errorf message = %q, want it to contain %q", log.errorf[0], "boom")
+	}
+}
+
+func TestLoggerSinkEventRouting(t *testing.T) {
+	log := &fakeLogger{}
+	s := NewLoggerSink(log)
+	s.Event("error", "bad thing")
+	s.Event("debug", "fine thing")
+
+	if len(log.errorf) != 1 {
+		t.Fatalf("Errorf calls = %d, want 1", len(log.errorf))
+	}
+	if len(log.debugf) != 1 {
+		t.Fatalf("Debugf calls = %d, want 1", len(log.debugf))
+	}
+}
+
+func TestJSONSinkEmitsNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONSink(&buf)
+	s.Start(PhaseDumpSource, 100)
+	s.Update(PhaseDumpSource, 42)
+	s.Event("warn", "careful")
+	s.Finish(PhaseDumpSource, errors.New("failed"))
+
+	dec := json.NewDecoder(&buf)
+	var events []jsonEvent
+	for dec.More() {
+		var e jsonEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("decoding event: %v", err)
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	if events[0].Type != "start" || events[0].Total != 100 {
+		t.Errorf("start event = %+v", events[0])
+	}
+	if events[1].Type != "update" || events[1].Done != 42 {
+		t.Errorf("update event = %+v", events[1])
+	}
+	if events[2].Type != "event" || events[2].Level != "warn" {
+		t.Errorf("event event = %+v", events[2])
+	}
+	if events[3].Type != "finish" || events[3].Err != "failed" {
+		t.Errorf("finish event = %+v", events[3])
+	}
+}
+
+func TestTTYSinkFinishMarksFailure(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewTTYSink(&buf)
+	s.Finish(PhaseHooks, errors.New("boom"))
+	if !strings.Contains(buf.String(), "boom") {
+		t.Errorf("output = %q, want it to contain %q", buf.String(), "boom")
+	}
+}
+
+func TestTTYSinkStartShowsTotal(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewTTYSink(&buf)
+	s.Start(PhaseConfEntry, 10)
+	if !strings.Contains(buf.String(), "0/10") {
+		t.Errorf("output = %q, want it to contain %q", buf.String(), "0/10")
+	}
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	ttyColorGreen  = "\x1b[32m"
+	ttyColorYellow = "\x1b[33m"
+	ttyColorRed    = "\x1b[31m"
+	ttyColorReset  = "\x1b[0m"
+)
+
+// TTYSink renders install phase progress as colored, human-readable lines,
+// for interactive terminal use (e.g. `kairos-agent manual-install`).
+type TTYSink struct {
+	out io.Writer
+}
+
+// NewTTYSink returns a Sink that writes colored progress lines to out.
+func NewTTYSink(out io.Writer) *TTYSink {
+	return &TTYSink{out: out}
+}
+
+func (s *TTYSink) Start(phase string, total int64) {
+	if total > 0 {
+		fmt.Fprintf(s.out, "%s==>%s %s (0/%d)\n", ttyColorYellow, ttyColorReset, phase, total)
+		return
+	}
+	fmt.Fprintf(s.out, "%s==>%s %s\n", ttyColorYellow, ttyColorReset, phase)
+}
+
+func (s *TTYSink) Update(phase string, done int64) {
+	fmt.Fprintf(s.out, "    %s: %d\n", phase, done)
+}
+
+func (s *TTYSink) Event(level, msg string) {
+	color := ttyColorReset
+	if level == "error" {
+		color = ttyColorRed
+	} else if level == "warn" {
+		color = ttyColorYellow
+	}
+	fmt.Fprintf(s.out, "%s%s%s\n", color, msg, ttyColorReset)
+}
+
+func (s *TTYSink) Finish(phase string, err error) {
+	if err != nil {
+		fmt.Fprintf(s.out, "%s✗ %s: %s%s\n", ttyColorRed, phase, err, ttyColorReset)
+		return
+	}
+	fmt.Fprintf(s.out, "%s✓ %s%s\n", ttyColorGreen, phase, ttyColorReset)
+}